@@ -0,0 +1,83 @@
+// Package audit records who changed a user's roles or permissions, when, and
+// what changed, so that mutations made through repositories.UserRepository
+// are no longer silent.
+package audit
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Kind identifies what a mutation acted on.
+type Kind string
+
+const (
+	KindRole       Kind = "role"
+	KindPermission Kind = "permission"
+)
+
+// Operation identifies how the IDs in an audit entry relate to the prior state.
+type Operation string
+
+const (
+	OperationGrant   Operation = "grant"
+	OperationRevoke  Operation = "revoke"
+	OperationReplace Operation = "replace"
+)
+
+// Recorder receives a notification for every role/permission mutation made
+// through a UserRepository. Implementations typically persist the entry
+// (see GormRecorder) and/or forward it to an EventSink.
+type Recorder interface {
+	// OnGrant is called after ids have been added to userID's kind within guard.
+	OnGrant(ctx context.Context, userID uuid.UUID, guard string, kind Kind, ids []uint, actor uuid.UUID) error
+	// OnRevoke is called after ids have been removed from userID's kind within guard.
+	OnRevoke(ctx context.Context, userID uuid.UUID, guard string, kind Kind, ids []uint, actor uuid.UUID) error
+	// OnReplace is called after userID's kind within guard has been replaced
+	// wholesale, reporting both the prior and the new set of IDs.
+	OnReplace(ctx context.Context, userID uuid.UUID, guard string, kind Kind, before []uint, after []uint, actor uuid.UUID) error
+}
+
+type contextKey int
+
+const (
+	actorContextKey contextKey = iota
+	requestIDContextKey
+)
+
+// WithActor attaches the acting user to ctx, so that repository mutations
+// performed with it are attributed to that actor in the audit trail.
+// @param context.Context
+// @param uuid.UUID
+// @return context.Context
+func WithActor(ctx context.Context, actor uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorContextKey, actor)
+}
+
+// ActorFromContext returns the actor attached by WithActor, or the zero UUID
+// if none was attached.
+// @param context.Context
+// @return uuid.UUID
+func ActorFromContext(ctx context.Context) uuid.UUID {
+	actor, _ := ctx.Value(actorContextKey).(uuid.UUID)
+	return actor
+}
+
+// WithRequestID attaches a request ID to ctx, so that entries recorded with
+// it can be correlated back to the request that produced them.
+// @param context.Context
+// @param string
+// @return context.Context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID attached by WithRequestID, or
+// "" if none was attached.
+// @param context.Context
+// @return string
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Event mirrors a Recorder notification, for forwarding to downstream
+// systems (cache invalidation, notifications, ...) through an EventSink.
+type Event struct {
+	UserID    uuid.UUID
+	Guard     string
+	Kind      Kind
+	Operation Operation
+	Before    []uint
+	After     []uint
+	Actor     uuid.UUID
+}
+
+// EventSink publishes audit events to a downstream system, e.g. a channel,
+// Kafka topic, or NATS subject.
+type EventSink interface {
+	Publish(ctx context.Context, event Event) error
+}
@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Log is the persisted record of a single role/permission mutation.
+type Log struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	ActorID   uuid.UUID `json:"actor_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Guard     string    `json:"guard"`
+	Kind      Kind      `json:"kind"`
+	Operation Operation `json:"operation"`
+	Before    string    `json:"before"`
+	After     string    `json:"after"`
+	RequestID string    `json:"request_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName sets the table name
+func (Log) TableName() string {
+	return "authz_audit_log"
+}
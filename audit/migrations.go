@@ -0,0 +1,12 @@
+package audit
+
+import "gorm.io/gorm"
+
+// Migrate brings the authz_audit_log table up to date, mirroring
+// pivot.Migrates for the pivot tables. It must be run before a Recorder is
+// wired up, otherwise every mutation's audit write will fail.
+// @param *gorm.DB
+// @return error
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Log{})
+}
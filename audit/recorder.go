@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+)
+
+// GormRecorder is the default Recorder, writing every mutation to the
+// authz_audit_log table and, if Sink is set, forwarding it as an Event too.
+type GormRecorder struct {
+	Database *gorm.DB
+	Sink     EventSink
+}
+
+// TxBinder is implemented by Recorders that can be rebound to a specific
+// *gorm.DB. UserRepository.WithTx uses it to rebind the Recorder to the
+// caller's transaction, so that a mutation composed inside a caller-owned
+// unit of work and its audit entry commit or roll back together instead of
+// racing each other on separate connections.
+type TxBinder interface {
+	WithTx(tx *gorm.DB) Recorder
+}
+
+// WithTx returns a copy of the recorder bound to tx.
+// @param *gorm.DB
+// @return Recorder
+func (recorder *GormRecorder) WithTx(tx *gorm.DB) Recorder {
+	return &GormRecorder{Database: tx, Sink: recorder.Sink}
+}
+
+// OnGrant records that ids were added to userID's kind within guard by actor.
+func (recorder *GormRecorder) OnGrant(ctx context.Context, userID uuid.UUID, guard string, kind Kind, ids []uint, actor uuid.UUID) error {
+	return recorder.record(ctx, userID, guard, kind, OperationGrant, nil, ids, actor)
+}
+
+// OnRevoke records that ids were removed from userID's kind within guard by actor.
+func (recorder *GormRecorder) OnRevoke(ctx context.Context, userID uuid.UUID, guard string, kind Kind, ids []uint, actor uuid.UUID) error {
+	return recorder.record(ctx, userID, guard, kind, OperationRevoke, ids, nil, actor)
+}
+
+// OnReplace records that userID's kind within guard went from before to after, by actor.
+func (recorder *GormRecorder) OnReplace(ctx context.Context, userID uuid.UUID, guard string, kind Kind, before []uint, after []uint, actor uuid.UUID) error {
+	return recorder.record(ctx, userID, guard, kind, OperationReplace, before, after, actor)
+}
+
+func (recorder *GormRecorder) record(ctx context.Context, userID uuid.UUID, guard string, kind Kind, operation Operation, before []uint, after []uint, actor uuid.UUID) error {
+	entry := Log{
+		ActorID:   actor,
+		UserID:    userID,
+		Guard:     guard,
+		Kind:      kind,
+		Operation: operation,
+		Before:    idsToJSON(before),
+		After:     idsToJSON(after),
+		RequestID: RequestIDFromContext(ctx),
+	}
+	if err := recorder.Database.WithContext(ctx).Create(&entry).Error; err != nil {
+		return err
+	}
+
+	if recorder.Sink == nil {
+		return nil
+	}
+	return recorder.Sink.Publish(ctx, Event{
+		UserID:    userID,
+		Guard:     guard,
+		Kind:      kind,
+		Operation: operation,
+		Before:    before,
+		After:     after,
+		Actor:     actor,
+	})
+}
+
+func idsToJSON(ids []uint) string {
+	if len(ids) == 0 {
+		return "[]"
+	}
+	encoded, err := json.Marshal(ids)
+	if err != nil {
+		return "[]"
+	}
+	return string(encoded)
+}
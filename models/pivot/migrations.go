@@ -0,0 +1,30 @@
+package pivot
+
+import "gorm.io/gorm"
+
+// DefaultGuardName is the guard used for assignments made before guard-based
+// multi-tenancy existed, and the fallback when no guard is specified.
+const DefaultGuardName = "web"
+
+// Migrates brings the pivot tables up to date with the guard_name column,
+// backfilling existing rows with DefaultGuardName so that pre-existing
+// role/permission assignments are found under that guard. A table created
+// here gets the guard-scoped primary key; on a table that already exists,
+// AutoMigrate adds the column but leaves the existing primary key unchanged.
+// @param *gorm.DB
+// @return error
+func Migrates(db *gorm.DB) error {
+	if err := db.AutoMigrate(&UserRoles{}, &UserPermissions{}, &RoleHierarchy{}, &RolePermissions{}); err != nil {
+		return err
+	}
+	if err := db.Exec("UPDATE user_roles SET guard_name = ? WHERE guard_name = ''", DefaultGuardName).Error; err != nil {
+		return err
+	}
+	if err := db.Exec("UPDATE user_permissions SET guard_name = ? WHERE guard_name = ''", DefaultGuardName).Error; err != nil {
+		return err
+	}
+	if err := db.Exec("UPDATE role_hierarchy SET guard_name = ? WHERE guard_name = ''", DefaultGuardName).Error; err != nil {
+		return err
+	}
+	return db.Exec("UPDATE role_permissions SET guard_name = ? WHERE guard_name = ''", DefaultGuardName).Error
+}
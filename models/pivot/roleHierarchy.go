@@ -0,0 +1,16 @@
+package pivot
+
+// RoleHierarchy represents the database model of role-to-role inheritance,
+// where a child role inherits every permission granted to its parent role.
+// GuardName scopes the edge itself, so the same pair of roles can inherit
+// from one another under one guard without doing so under another.
+type RoleHierarchy struct {
+	ParentRoleID uint   `gorm:"primary_key" json:"parent_role_id"`
+	ChildRoleID  uint   `gorm:"primary_key" json:"child_role_id"`
+	GuardName    string `gorm:"primary_key;default:'web'" json:"guard_name"`
+}
+
+// TableName sets the table name
+func (RoleHierarchy) TableName() string {
+	return "role_hierarchy"
+}
@@ -0,0 +1,15 @@
+package pivot
+
+// RolePermissions represents the database model of role-to-permission
+// relationships. GuardName scopes the grant itself, so a role can carry
+// different permissions under different guards.
+type RolePermissions struct {
+	RoleID       uint   `gorm:"primary_key" json:"role_id"`
+	PermissionID uint   `gorm:"primary_key" json:"permission_id"`
+	GuardName    string `gorm:"primary_key;default:'web'" json:"guard_name"`
+}
+
+// TableName sets the table name
+func (RolePermissions) TableName() string {
+	return "role_permissions"
+}
@@ -0,0 +1,25 @@
+package pivot
+
+import "fmt"
+
+// RoleTreeCTE returns a `WITH RECURSIVE role_tree AS (...)` fragment that
+// expands a set of "root" role ids (given by rootsQuery, a SELECT returning a
+// single role_id column) to include every ancestor reachable through
+// role_hierarchy within the given guard, so a role's permissions are
+// inherited by its descendants under that guard only.
+// rootsQuery is embedded verbatim, so its placeholders (if any) must be bound
+// first, immediately followed by a guard placeholder for the role_hierarchy
+// walk this fragment adds, in the same left-to-right order as the rest of
+// the query this fragment is prepended to.
+// @param string
+// @return string
+func RoleTreeCTE(rootsQuery string) string {
+	return fmt.Sprintf(`
+		WITH RECURSIVE role_tree AS (
+			%s
+			UNION
+			SELECT rh.parent_role_id FROM role_hierarchy rh
+			INNER JOIN role_tree rt ON rh.child_role_id = rt.role_id AND rh.guard_name = ?
+		)
+	`, rootsQuery)
+}
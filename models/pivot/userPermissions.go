@@ -6,6 +6,7 @@ import "github.com/google/uuid"
 type UserPermissions struct {
 	UserID       uuid.UUID `gorm:"primary_key" json:"user_id"`
 	PermissionID uint      `gorm:"primary_key" json:"permission_id"`
+	GuardName    string    `gorm:"primary_key;default:'web'" json:"guard_name"`
 }
 
 // TableName sets the table name
@@ -4,8 +4,9 @@ import "github.com/google/uuid"
 
 // UserRoles represents the database model of user roles relationships
 type UserRoles struct {
-	UserID uuid.UUID `gorm:"primary_key" json:"user_id"`
-	RoleID uint      `gorm:"primary_key" json:"role_id"`
+	UserID    uuid.UUID `gorm:"primary_key" json:"user_id"`
+	RoleID    uint      `gorm:"primary_key" json:"role_id"`
+	GuardName string    `gorm:"primary_key;default:'web'" json:"guard_name"`
 }
 
 // TableName sets the table name
@@ -0,0 +1,75 @@
+// Package policy implements a small boolean expression language over roles
+// and permissions, e.g. `role:admin OR (perm:orders.read AND perm:orders.write) AND NOT role:banned`.
+//
+// Expressions are either parsed from a string with Parse, or built
+// programmatically with the helpers in builder.go, then evaluated against a
+// user's effective roles and permissions with Evaluate.
+package policy
+
+// Facts is the set of role and permission names a user currently holds,
+// prefetched once so a Node tree can be evaluated in Go without further
+// queries.
+type Facts struct {
+	Roles       map[string]bool
+	Permissions map[string]bool
+}
+
+// Node is a boolean expression over Facts.
+type Node interface {
+	Eval(facts Facts) bool
+}
+
+// andNode is true when every child node is true.
+type andNode struct {
+	children []Node
+}
+
+func (n andNode) Eval(facts Facts) bool {
+	for _, child := range n.children {
+		if !child.Eval(facts) {
+			return false
+		}
+	}
+	return true
+}
+
+// orNode is true when any child node is true.
+type orNode struct {
+	children []Node
+}
+
+func (n orNode) Eval(facts Facts) bool {
+	for _, child := range n.children {
+		if child.Eval(facts) {
+			return true
+		}
+	}
+	return false
+}
+
+// notNode negates its child.
+type notNode struct {
+	child Node
+}
+
+func (n notNode) Eval(facts Facts) bool {
+	return !n.child.Eval(facts)
+}
+
+// roleLit is true when the user holds the named role.
+type roleLit struct {
+	name string
+}
+
+func (n roleLit) Eval(facts Facts) bool {
+	return facts.Roles[n.name]
+}
+
+// permLit is true when the user holds the named permission.
+type permLit struct {
+	name string
+}
+
+func (n permLit) Eval(facts Facts) bool {
+	return facts.Permissions[n.name]
+}
@@ -0,0 +1,62 @@
+package policy
+
+// Role builds a leaf node that is true when the user holds the named role.
+func Role(name string) Node {
+	return roleLit{name: name}
+}
+
+// Perm builds a leaf node that is true when the user holds the named permission.
+func Perm(name string) Node {
+	return permLit{name: name}
+}
+
+// Not negates the given node.
+func Not(node Node) Node {
+	return notNode{child: node}
+}
+
+// All builds a node that is true only when every given node is true.
+func All(nodes ...Node) Node {
+	return andNode{children: nodes}
+}
+
+// Any builds a node that is true when any of the given nodes is true.
+func Any(nodes ...Node) Node {
+	return orNode{children: nodes}
+}
+
+// AllRoles builds a node that is true when the user holds every named role.
+func AllRoles(names ...string) Node {
+	return All(roleLits(names)...)
+}
+
+// AnyRole builds a node that is true when the user holds any of the named roles.
+func AnyRole(names ...string) Node {
+	return Any(roleLits(names)...)
+}
+
+// AllPerm builds a node that is true when the user holds every named permission.
+func AllPerm(names ...string) Node {
+	return All(permLits(names)...)
+}
+
+// AnyPerm builds a node that is true when the user holds any of the named permissions.
+func AnyPerm(names ...string) Node {
+	return Any(permLits(names)...)
+}
+
+func roleLits(names []string) []Node {
+	nodes := make([]Node, len(names))
+	for i, name := range names {
+		nodes[i] = Role(name)
+	}
+	return nodes
+}
+
+func permLits(names []string) []Node {
+	nodes := make([]Node, len(names))
+	for i, name := range names {
+		nodes[i] = Perm(name)
+	}
+	return nodes
+}
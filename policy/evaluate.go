@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/Permify/go-role/models/pivot"
+	"github.com/google/uuid"
+)
+
+// Evaluate parses expr and evaluates it against the user's effective roles
+// and permissions within the given guard (direct roles/permissions plus
+// those inherited through role hierarchy), fetching each set with a single
+// query.
+// @param context.Context
+// @param *gorm.DB
+// @param uuid.UUID
+// @param string
+// @param string
+// @return bool, error
+func Evaluate(ctx context.Context, db *gorm.DB, userID uuid.UUID, guard string, expr string) (bool, error) {
+	node, err := Parse(expr)
+	if err != nil {
+		return false, err
+	}
+
+	facts, err := fetchFacts(ctx, db, userID, guard)
+	if err != nil {
+		return false, err
+	}
+
+	return node.Eval(facts), nil
+}
+
+// EvaluateNode evaluates an already-built Node tree (see builder.go) against
+// the user's effective roles and permissions within the given guard.
+// @param context.Context
+// @param *gorm.DB
+// @param uuid.UUID
+// @param string
+// @param Node
+// @return bool, error
+func EvaluateNode(ctx context.Context, db *gorm.DB, userID uuid.UUID, guard string, node Node) (bool, error) {
+	facts, err := fetchFacts(ctx, db, userID, guard)
+	if err != nil {
+		return false, err
+	}
+	return node.Eval(facts), nil
+}
+
+func fetchFacts(ctx context.Context, db *gorm.DB, userID uuid.UUID, guard string) (Facts, error) {
+	// facts.Roles is the user's own, directly-assigned roles: role:X in a
+	// policy means "holds role X", not "holds X or any of its descendants",
+	// so this must not walk the role_tree used below for permission
+	// inheritance.
+	var roleNames []string
+	err := db.WithContext(ctx).Raw(`
+		SELECT DISTINCT r.name FROM roles r
+		INNER JOIN user_roles ur ON ur.role_id = r.id
+		WHERE ur.user_id = ? AND ur.guard_name = ?
+	`, userID, guard).Scan(&roleNames).Error
+	if err != nil {
+		return Facts{}, err
+	}
+
+	roleTreeCTE := pivot.RoleTreeCTE(`SELECT role_id FROM user_roles WHERE user_id = ? AND guard_name = ?`)
+
+	var permissionNames []string
+	err = db.WithContext(ctx).Raw(
+		roleTreeCTE+`
+		SELECT DISTINCT p.name FROM permissions p
+		INNER JOIN role_permissions rp ON rp.permission_id = p.id
+		INNER JOIN role_tree rt ON rt.role_id = rp.role_id AND rp.guard_name = ?
+		UNION
+		SELECT DISTINCT p.name FROM permissions p
+		INNER JOIN user_permissions up ON up.permission_id = p.id
+		WHERE up.user_id = ? AND up.guard_name = ?
+	`, userID, guard, guard, guard, userID, guard).Scan(&permissionNames).Error
+	if err != nil {
+		return Facts{}, err
+	}
+
+	facts := Facts{
+		Roles:       make(map[string]bool, len(roleNames)),
+		Permissions: make(map[string]bool, len(permissionNames)),
+	}
+	for _, name := range roleNames {
+		facts.Roles[name] = true
+	}
+	for _, name := range permissionNames {
+		facts.Permissions[name] = true
+	}
+	return facts, nil
+}
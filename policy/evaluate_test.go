@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/Permify/go-role/models"
+	"github.com/Permify/go-role/models/pivot"
+)
+
+// TestFetchFacts_HierarchyDirection mirrors
+// TestGetAllPermissionsOfRoles_HierarchyDirection in the repositories
+// package: it pins down that fetchFacts' role_tree CTE walks up to a role's
+// ancestors, so a user holding a child role satisfies a policy on a
+// permission that only the parent role holds, while role:X still matches
+// only the roles assigned to the user directly, never the parent.
+func TestFetchFacts_HierarchyDirection(t *testing.T) {
+	db := newTestDB(t)
+	userID := uuid.New()
+
+	parent := models.Role{Name: "admin"}
+	child := models.Role{Name: "editor"}
+	if err := db.Create(&parent).Error; err != nil {
+		t.Fatalf("create parent role: %v", err)
+	}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatalf("create child role: %v", err)
+	}
+	if err := db.Create(&pivot.RoleHierarchy{ParentRoleID: parent.ID, ChildRoleID: child.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("create role hierarchy: %v", err)
+	}
+
+	parentOnlyPermission := models.Permission{Name: "users.manage"}
+	if err := db.Create(&parentOnlyPermission).Error; err != nil {
+		t.Fatalf("create permission: %v", err)
+	}
+	if err := db.Create(&pivot.RolePermissions{RoleID: parent.ID, PermissionID: parentOnlyPermission.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("grant permission to parent role: %v", err)
+	}
+
+	if err := db.Create(&pivot.UserRoles{UserID: userID, RoleID: child.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("assign child role to user: %v", err)
+	}
+
+	childOK, err := Evaluate(context.Background(), db, userID, "web", "role:editor AND perm:users.manage")
+	if err != nil {
+		t.Fatalf("Evaluate(child holder): %v", err)
+	}
+	if !childOK {
+		t.Errorf("user holding child role %q should inherit parent-only permission %q", child.Name, parentOnlyPermission.Name)
+	}
+
+	adminHeld, err := Evaluate(context.Background(), db, userID, "web", "role:admin")
+	if err != nil {
+		t.Fatalf("Evaluate(role:admin): %v", err)
+	}
+	if adminHeld {
+		t.Errorf("user holding only child role %q was incorrectly reported as holding parent role %q (hierarchy traversed the wrong direction)", child.Name, parent.Name)
+	}
+}
+
+// TestFetchFacts_GuardScoping pins down that fetchFacts scopes the user's
+// directly assigned roles to the given guard: a role assigned under one
+// guard is not visible to Evaluate under another.
+func TestFetchFacts_GuardScoping(t *testing.T) {
+	db := newTestDB(t)
+	userID := uuid.New()
+
+	role := models.Role{Name: "editor"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	if err := db.Create(&pivot.UserRoles{UserID: userID, RoleID: role.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("assign role under guard web: %v", err)
+	}
+
+	webOK, err := Evaluate(context.Background(), db, userID, "web", "role:editor")
+	if err != nil {
+		t.Fatalf("Evaluate(web): %v", err)
+	}
+	if !webOK {
+		t.Errorf("role assigned under guard %q should be visible to Evaluate under the same guard", "web")
+	}
+
+	apiOK, err := Evaluate(context.Background(), db, userID, "api", "role:editor")
+	if err != nil {
+		t.Fatalf("Evaluate(api): %v", err)
+	}
+	if apiOK {
+		t.Errorf("role assigned under guard %q leaked into guard %q", "web", "api")
+	}
+}
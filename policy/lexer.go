@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenRole
+	tokenPerm
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// lex splits a policy expression into tokens. `role:x` and `perm:x` literals
+// must not contain whitespace or parentheses; everything else is whitespace
+// and parenthesis delimited.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	var word strings.Builder
+
+	flush := func() error {
+		if word.Len() == 0 {
+			return nil
+		}
+		tok, err := wordToken(word.String())
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens, tok)
+		word.Reset()
+		return nil
+	}
+
+	for _, r := range expr {
+		switch {
+		case r == '(' || r == ')':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if r == '(' {
+				tokens = append(tokens, token{kind: tokenLParen})
+			} else {
+				tokens = append(tokens, token{kind: tokenRParen})
+			}
+		case r == ' ' || r == '\t' || r == '\n':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func wordToken(word string) (token, error) {
+	switch strings.ToUpper(word) {
+	case "AND":
+		return token{kind: tokenAnd}, nil
+	case "OR":
+		return token{kind: tokenOr}, nil
+	case "NOT":
+		return token{kind: tokenNot}, nil
+	}
+
+	switch {
+	case strings.HasPrefix(word, "role:"):
+		return token{kind: tokenRole, value: strings.TrimPrefix(word, "role:")}, nil
+	case strings.HasPrefix(word, "perm:"):
+		return token{kind: tokenPerm, value: strings.TrimPrefix(word, "perm:")}, nil
+	}
+
+	return token{}, fmt.Errorf("policy: unrecognized token %q", word)
+}
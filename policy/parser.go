@@ -0,0 +1,133 @@
+package policy
+
+import "fmt"
+
+// Parse compiles a policy expression, e.g.
+// `role:admin OR (perm:orders.read AND perm:orders.write) AND NOT role:banned`,
+// into a Node tree. NOT binds tighter than AND, which binds tighter than OR.
+func Parse(expr string) (Node, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("policy: empty expression")
+	}
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("policy: unexpected token after expression")
+	}
+	return node, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) next() (token, bool) {
+	tok, ok := p.peek()
+	if ok {
+		p.pos++
+	}
+	return tok, ok
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{left}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOr {
+			break
+		}
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return orNode{children: nodes}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{left}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenAnd {
+			break
+		}
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, right)
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return andNode{children: nodes}, nil
+}
+
+func (p *parser) parseNot() (Node, error) {
+	tok, ok := p.peek()
+	if ok && tok.kind == tokenNot {
+		p.next()
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok, ok := p.next()
+	if !ok {
+		return nil, fmt.Errorf("policy: unexpected end of expression")
+	}
+
+	switch tok.kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, ok := p.next()
+		if !ok || closing.kind != tokenRParen {
+			return nil, fmt.Errorf("policy: missing closing parenthesis")
+		}
+		return node, nil
+	case tokenRole:
+		return roleLit{name: tok.value}, nil
+	case tokenPerm:
+		return permLit{name: tok.value}, nil
+	default:
+		return nil, fmt.Errorf("policy: unexpected token in expression")
+	}
+}
@@ -0,0 +1,190 @@
+package policy
+
+import "testing"
+
+func TestParseEval(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		facts Facts
+		want  bool
+	}{
+		{
+			name: "single role literal, held",
+			expr: "role:admin",
+			facts: Facts{
+				Roles: map[string]bool{"admin": true},
+			},
+			want: true,
+		},
+		{
+			name:  "single role literal, not held",
+			expr:  "role:admin",
+			facts: Facts{},
+			want:  false,
+		},
+		{
+			name: "single perm literal, held",
+			expr: "perm:orders.read",
+			facts: Facts{
+				Permissions: map[string]bool{"orders.read": true},
+			},
+			want: true,
+		},
+		{
+			name: "AND binds tighter than OR",
+			expr: "role:admin OR role:editor AND role:banned",
+			facts: Facts{
+				Roles: map[string]bool{"admin": true},
+			},
+			// Must parse as `role:admin OR (role:editor AND role:banned)`,
+			// so admin alone is enough regardless of editor/banned.
+			want: true,
+		},
+		{
+			name: "AND binds tighter than OR, right side false",
+			expr: "role:editor AND role:banned OR role:admin",
+			facts: Facts{
+				Roles: map[string]bool{"editor": true},
+			},
+			// `(role:editor AND role:banned) OR role:admin`: editor alone,
+			// without banned or admin, is not enough.
+			want: false,
+		},
+		{
+			name: "NOT binds tighter than AND",
+			expr: "NOT role:banned AND role:admin",
+			facts: Facts{
+				Roles: map[string]bool{"admin": true},
+			},
+			// `(NOT role:banned) AND role:admin`
+			want: true,
+		},
+		{
+			name: "NOT binds tighter than AND, negation applies only to first operand",
+			expr: "NOT role:admin AND role:admin",
+			facts: Facts{
+				Roles: map[string]bool{"admin": true},
+			},
+			want: false,
+		},
+		{
+			name: "double negation",
+			expr: "NOT NOT role:admin",
+			facts: Facts{
+				Roles: map[string]bool{"admin": true},
+			},
+			want: true,
+		},
+		{
+			name:  "double negation, false",
+			expr:  "NOT NOT role:admin",
+			facts: Facts{},
+			want:  false,
+		},
+		{
+			name: "parentheses override precedence",
+			expr: "(role:admin OR role:editor) AND role:banned",
+			facts: Facts{
+				Roles: map[string]bool{"admin": true},
+			},
+			want: false,
+		},
+		{
+			name: "parentheses override precedence, true",
+			expr: "(role:admin OR role:editor) AND role:banned",
+			facts: Facts{
+				Roles: map[string]bool{"editor": true, "banned": true},
+			},
+			want: true,
+		},
+		{
+			name: "nested parentheses with NOT",
+			expr: "role:admin OR (perm:orders.read AND NOT role:banned)",
+			facts: Facts{
+				Permissions: map[string]bool{"orders.read": true},
+			},
+			want: true,
+		},
+		{
+			name: "token keywords are case-insensitive",
+			expr: "role:admin and not role:banned",
+			facts: Facts{
+				Roles: map[string]bool{"admin": true},
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned unexpected error: %v", tt.expr, err)
+			}
+			if got := node.Eval(tt.facts); got != tt.want {
+				t.Errorf("Parse(%q).Eval(%+v) = %v, want %v", tt.expr, tt.facts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "empty expression", expr: ""},
+		{name: "whitespace only", expr: "   "},
+		{name: "unmatched opening paren", expr: "(role:admin"},
+		{name: "unmatched closing paren", expr: "role:admin)"},
+		{name: "dangling operator", expr: "role:admin AND"},
+		{name: "dangling NOT", expr: "NOT"},
+		{name: "unrecognized token", expr: "role:admin MAYBE role:editor"},
+		{name: "literal without prefix", expr: "admin"},
+		{name: "trailing token after valid expression", expr: "role:admin role:editor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.expr); err == nil {
+				t.Errorf("Parse(%q) returned no error, want one", tt.expr)
+			}
+		})
+	}
+}
+
+func TestBuilders(t *testing.T) {
+	facts := Facts{
+		Roles:       map[string]bool{"admin": true},
+		Permissions: map[string]bool{"orders.read": true},
+	}
+
+	tests := []struct {
+		name string
+		node Node
+		want bool
+	}{
+		{name: "Role held", node: Role("admin"), want: true},
+		{name: "Role not held", node: Role("editor"), want: false},
+		{name: "Perm held", node: Perm("orders.read"), want: true},
+		{name: "Not negates", node: Not(Role("editor")), want: true},
+		{name: "All requires every node", node: All(Role("admin"), Perm("orders.read")), want: true},
+		{name: "All fails if one node is false", node: All(Role("admin"), Role("editor")), want: false},
+		{name: "Any succeeds if one node is true", node: Any(Role("editor"), Role("admin")), want: true},
+		{name: "Any fails if every node is false", node: Any(Role("editor"), Role("banned")), want: false},
+		{name: "AllRoles", node: AllRoles("admin"), want: true},
+		{name: "AllRoles fails on missing role", node: AllRoles("admin", "editor"), want: false},
+		{name: "AnyRole", node: AnyRole("editor", "admin"), want: true},
+		{name: "AllPerm", node: AllPerm("orders.read"), want: true},
+		{name: "AnyPerm", node: AnyPerm("orders.write", "orders.read"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.Eval(facts); got != tt.want {
+				t.Errorf("Eval(%+v) = %v, want %v", facts, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/Permify/go-role/collections"
+	"github.com/Permify/go-role/models"
+	"github.com/Permify/go-role/models/pivot"
+)
+
+// IRoleRepository its data access layer abstraction of role.
+type IRoleRepository interface {
+	GetAllPermissionsOfRoles(roles collections.Role, guard string) (permissions collections.Permission, err error)
+}
+
+// RoleRepository its data access layer of role.
+type RoleRepository struct {
+	Database *gorm.DB
+}
+
+// GetAllPermissionsOfRoles returns the deduplicated permissions granted to the
+// given roles within the given guard, including permissions inherited from
+// their parent roles under that same guard.
+// @param collections.Role
+// @param string
+// @return collections.Permission, error
+func (repository *RoleRepository) GetAllPermissionsOfRoles(roles collections.Role, guard string) (collections.Permission, error) {
+	var permissions []models.Permission
+	err := repository.Database.Raw(
+		pivot.RoleTreeCTE(`SELECT id AS role_id FROM roles WHERE id IN (?)`)+`
+		SELECT DISTINCT p.* FROM permissions p
+		INNER JOIN role_permissions rp ON rp.permission_id = p.id
+		INNER JOIN role_tree rt ON rt.role_id = rp.role_id AND rp.guard_name = ?
+	`, roles.IDs(), guard, guard).Scan(&permissions).Error
+	if err != nil {
+		return collections.Permission{}, err
+	}
+	return collections.Permission(permissions), nil
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/Permify/go-role/collections"
+	"github.com/Permify/go-role/models"
+	"github.com/Permify/go-role/models/pivot"
+)
+
+// TestGetAllPermissionsOfRoles_HierarchyDirection pins down the direction of
+// role inheritance: a child role inherits its parent's permissions, but a
+// parent role must not gain permissions that only its children hold.
+func TestGetAllPermissionsOfRoles_HierarchyDirection(t *testing.T) {
+	db := newTestDB(t)
+	repository := &RoleRepository{Database: db}
+
+	parent := models.Role{Name: "admin"}
+	child := models.Role{Name: "editor"}
+	if err := db.Create(&parent).Error; err != nil {
+		t.Fatalf("create parent role: %v", err)
+	}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatalf("create child role: %v", err)
+	}
+	if err := db.Create(&pivot.RoleHierarchy{ParentRoleID: parent.ID, ChildRoleID: child.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("create role hierarchy: %v", err)
+	}
+
+	parentOnlyPermission := models.Permission{Name: "users.manage"}
+	childOnlyPermission := models.Permission{Name: "posts.edit"}
+	if err := db.Create(&parentOnlyPermission).Error; err != nil {
+		t.Fatalf("create parent permission: %v", err)
+	}
+	if err := db.Create(&childOnlyPermission).Error; err != nil {
+		t.Fatalf("create child permission: %v", err)
+	}
+	if err := db.Create(&pivot.RolePermissions{RoleID: parent.ID, PermissionID: parentOnlyPermission.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("grant permission to parent: %v", err)
+	}
+	if err := db.Create(&pivot.RolePermissions{RoleID: child.ID, PermissionID: childOnlyPermission.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("grant permission to child: %v", err)
+	}
+
+	childPermissions, err := repository.GetAllPermissionsOfRoles(collections.Role{child}, "web")
+	if err != nil {
+		t.Fatalf("GetAllPermissionsOfRoles(child): %v", err)
+	}
+	if !containsPermissionID(childPermissions, parentOnlyPermission.ID) {
+		t.Errorf("child role %q did not inherit parent-only permission %q", child.Name, parentOnlyPermission.Name)
+	}
+
+	parentPermissions, err := repository.GetAllPermissionsOfRoles(collections.Role{parent}, "web")
+	if err != nil {
+		t.Fatalf("GetAllPermissionsOfRoles(parent): %v", err)
+	}
+	if containsPermissionID(parentPermissions, childOnlyPermission.ID) {
+		t.Errorf("parent role %q incorrectly gained child-only permission %q (hierarchy traversed the wrong direction)", parent.Name, childOnlyPermission.Name)
+	}
+}
+
+// TestGetAllPermissionsOfRoles_GuardIsolation pins down that a permission
+// which a child role inherits under one guard, through a grant and an
+// inheritance edge both recorded under that guard, is not visible when
+// resolving the same role under another guard.
+func TestGetAllPermissionsOfRoles_GuardIsolation(t *testing.T) {
+	db := newTestDB(t)
+	repository := &RoleRepository{Database: db}
+
+	parent := models.Role{Name: "admin"}
+	child := models.Role{Name: "editor"}
+	if err := db.Create(&parent).Error; err != nil {
+		t.Fatalf("create parent role: %v", err)
+	}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatalf("create child role: %v", err)
+	}
+	if err := db.Create(&pivot.RoleHierarchy{ParentRoleID: parent.ID, ChildRoleID: child.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("create role hierarchy under guard web: %v", err)
+	}
+
+	webOnlyPermission := models.Permission{Name: "users.manage"}
+	if err := db.Create(&webOnlyPermission).Error; err != nil {
+		t.Fatalf("create permission: %v", err)
+	}
+	if err := db.Create(&pivot.RolePermissions{RoleID: parent.ID, PermissionID: webOnlyPermission.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("grant permission under guard web: %v", err)
+	}
+
+	webPermissions, err := repository.GetAllPermissionsOfRoles(collections.Role{child}, "web")
+	if err != nil {
+		t.Fatalf("GetAllPermissionsOfRoles(web): %v", err)
+	}
+	if !containsPermissionID(webPermissions, webOnlyPermission.ID) {
+		t.Errorf("child role %q should inherit permission %q under guard %q, its own guard", child.Name, webOnlyPermission.Name, "web")
+	}
+
+	apiPermissions, err := repository.GetAllPermissionsOfRoles(collections.Role{child}, "api")
+	if err != nil {
+		t.Fatalf("GetAllPermissionsOfRoles(api): %v", err)
+	}
+	if containsPermissionID(apiPermissions, webOnlyPermission.ID) {
+		t.Errorf("permission %q granted under guard %q leaked into guard %q", webOnlyPermission.Name, "web", "api")
+	}
+}
+
+func containsPermissionID(permissions collections.Permission, id uint) bool {
+	for _, permission := range permissions.Origin() {
+		if permission.ID == id {
+			return true
+		}
+	}
+	return false
+}
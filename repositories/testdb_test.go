@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Permify/go-role/audit"
+	"github.com/Permify/go-role/models"
+	"github.com/Permify/go-role/models/pivot"
+)
+
+// newTestDB returns an in-memory sqlite database, private to t and closed
+// when it finishes, with every table this package's repositories touch
+// already migrated.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get underlying sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.Role{}, &models.Permission{}); err != nil {
+		t.Fatalf("migrate models: %v", err)
+	}
+	if err := pivot.Migrates(db); err != nil {
+		t.Fatalf("pivot.Migrates: %v", err)
+	}
+	if err := audit.Migrate(db); err != nil {
+		t.Fatalf("audit.Migrate: %v", err)
+	}
+	return db
+}
@@ -1,9 +1,13 @@
 package repositories
 
 import (
+	"context"
+	"log"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"github.com/Permify/go-role/audit"
 	"github.com/Permify/go-role/collections"
 	"github.com/Permify/go-role/models"
 	"github.com/Permify/go-role/models/pivot"
@@ -11,59 +15,215 @@ import (
 )
 
 // IUserRepository its data access layer abstraction of user.
+//
+// Every action and control takes a guard name so the same user can hold
+// different roles/permissions in different guards (e.g. "web" vs "api", or
+// per-tenant). Pass pivot.DefaultGuardName when guards are not in use.
 type IUserRepository interface {
+	// composition
+
+	WithTx(tx *gorm.DB) IUserRepository
+
 	// actions
 
-	AddPermissions(userID uuid.UUID, permissions collections.Permission) (err error)
-	ReplacePermissions(userID uuid.UUID, permissions collections.Permission) (err error)
-	RemovePermissions(userID uuid.UUID, permissions collections.Permission) (err error)
-	ClearPermissions(userID uuid.UUID) (err error)
+	AddPermissions(userID uuid.UUID, guard string, permissions collections.Permission) (err error)
+	AddPermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) (err error)
+	ReplacePermissions(userID uuid.UUID, guard string, permissions collections.Permission) (err error)
+	ReplacePermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) (err error)
+	RemovePermissions(userID uuid.UUID, guard string, permissions collections.Permission) (err error)
+	RemovePermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) (err error)
+	ClearPermissions(userID uuid.UUID, guard string) (err error)
+	ClearPermissionsContext(ctx context.Context, userID uuid.UUID, guard string) (err error)
 
-	AddRoles(userID uuid.UUID, roles collections.Role) (err error)
-	ReplaceRoles(userID uuid.UUID, roles collections.Role) (err error)
-	RemoveRoles(userID uuid.UUID, roles collections.Role) (err error)
-	ClearRoles(userID uuid.UUID) (err error)
+	AddRoles(userID uuid.UUID, guard string, roles collections.Role) (err error)
+	AddRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) (err error)
+	ReplaceRoles(userID uuid.UUID, guard string, roles collections.Role) (err error)
+	ReplaceRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) (err error)
+	RemoveRoles(userID uuid.UUID, guard string, roles collections.Role) (err error)
+	RemoveRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) (err error)
+	ClearRoles(userID uuid.UUID, guard string) (err error)
+	ClearRolesContext(ctx context.Context, userID uuid.UUID, guard string) (err error)
 
 	// controls
 
-	HasRole(userID uuid.UUID, role models.Role) (b bool, err error)
-	HasAllRoles(userID uuid.UUID, roles collections.Role) (b bool, err error)
-	HasAnyRoles(userID uuid.UUID, roles collections.Role) (b bool, err error)
+	HasRole(userID uuid.UUID, guard string, role models.Role) (b bool, err error)
+	HasRoleContext(ctx context.Context, userID uuid.UUID, guard string, role models.Role) (b bool, err error)
+	HasAllRoles(userID uuid.UUID, guard string, roles collections.Role) (b bool, err error)
+	HasAllRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) (b bool, err error)
+	HasAnyRoles(userID uuid.UUID, guard string, roles collections.Role) (b bool, err error)
+	HasAnyRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) (b bool, err error)
+
+	HasDirectPermission(userID uuid.UUID, guard string, permission models.Permission) (b bool, err error)
+	HasDirectPermissionContext(ctx context.Context, userID uuid.UUID, guard string, permission models.Permission) (b bool, err error)
+	HasAllDirectPermissions(userID uuid.UUID, guard string, permissions collections.Permission) (b bool, err error)
+	HasAllDirectPermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) (b bool, err error)
+	HasAnyDirectPermissions(userID uuid.UUID, guard string, permissions collections.Permission) (b bool, err error)
+	HasAnyDirectPermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) (b bool, err error)
 
-	HasDirectPermission(userID uuid.UUID, permission models.Permission) (b bool, err error)
-	HasAllDirectPermissions(userID uuid.UUID, permissions collections.Permission) (b bool, err error)
-	HasAnyDirectPermissions(userID uuid.UUID, permissions collections.Permission) (b bool, err error)
+	// effective (direct + role-inherited) permissions
+
+	GetAllPermissionsOfUser(userID uuid.UUID, guard string) (permissions collections.Permission, err error)
+	GetAllPermissionsOfUserContext(ctx context.Context, userID uuid.UUID, guard string) (permissions collections.Permission, err error)
+	HasPermission(userID uuid.UUID, guard string, permission models.Permission) (b bool, err error)
+	HasPermissionContext(ctx context.Context, userID uuid.UUID, guard string, permission models.Permission) (b bool, err error)
+
+	// bulk operations
+
+	SyncUserRoles(guard string, assignments map[uuid.UUID]collections.Role) (err error)
+	SyncUserRolesContext(ctx context.Context, guard string, assignments map[uuid.UUID]collections.Role) (err error)
+	SyncUserPermissions(guard string, assignments map[uuid.UUID]collections.Permission) (err error)
+	SyncUserPermissionsContext(ctx context.Context, guard string, assignments map[uuid.UUID]collections.Permission) (err error)
+	HasRolesForUsers(userIDs []uuid.UUID, guard string, role models.Role) (result map[uuid.UUID]bool, err error)
+	HasRolesForUsersContext(ctx context.Context, userIDs []uuid.UUID, guard string, role models.Role) (result map[uuid.UUID]bool, err error)
 }
 
+var _ IUserRepository = (*UserRepository)(nil)
+
 // UserRepository its data access layer of user.
 type UserRepository struct {
 	Database *gorm.DB
+	// Recorder, when set, is notified of every role/permission mutation made
+	// through the repository. The actor recorded is read from ctx via
+	// audit.ActorFromContext, so it is only populated on *Context methods.
+	Recorder audit.Recorder
+}
+
+// WithTx returns a copy of the repository bound to the given transaction, so
+// role/permission mutations can be composed inside a caller-owned unit of work
+// (e.g. "create user + assign default roles + grant permissions" atomically).
+// If Recorder implements audit.TxBinder (as the default audit.GormRecorder
+// does), it is rebound to tx too, so its audit entries commit or roll back
+// together with the rest of the unit of work instead of racing it on a
+// separate connection.
+// @param *gorm.DB
+// @return IUserRepository
+func (repository *UserRepository) WithTx(tx *gorm.DB) IUserRepository {
+	recorder := repository.Recorder
+	if binder, ok := recorder.(audit.TxBinder); ok {
+		recorder = binder.WithTx(tx)
+	}
+	return &UserRepository{Database: tx, Recorder: recorder}
+}
+
+// recordGrant, recordRevoke and recordReplace notify Recorder, if set, of a
+// mutation that has already been written to the database (committed, or,
+// under WithTx, pending in the caller's transaction). The mutation has
+// already happened by the time these are called, so a Recorder failure (e.g.
+// the audit log table doesn't exist yet) is logged rather than returned: it
+// must never make an already-successful grant/revoke/replace look like it
+// failed.
+
+func (repository *UserRepository) recordGrant(ctx context.Context, userID uuid.UUID, guard string, kind audit.Kind, ids []uint) {
+	if repository.Recorder == nil || len(ids) == 0 {
+		return
+	}
+	if err := repository.Recorder.OnGrant(ctx, userID, guard, kind, ids, audit.ActorFromContext(ctx)); err != nil {
+		log.Printf("go-role: audit: failed to record grant of %s %v to user %s under guard %q: %v", kind, ids, userID, guard, err)
+	}
+}
+
+func (repository *UserRepository) recordRevoke(ctx context.Context, userID uuid.UUID, guard string, kind audit.Kind, ids []uint) {
+	if repository.Recorder == nil || len(ids) == 0 {
+		return
+	}
+	if err := repository.Recorder.OnRevoke(ctx, userID, guard, kind, ids, audit.ActorFromContext(ctx)); err != nil {
+		log.Printf("go-role: audit: failed to record revoke of %s %v from user %s under guard %q: %v", kind, ids, userID, guard, err)
+	}
+}
+
+func (repository *UserRepository) recordReplace(ctx context.Context, userID uuid.UUID, guard string, kind audit.Kind, before []uint, after []uint) {
+	if repository.Recorder == nil {
+		return
+	}
+	if err := repository.Recorder.OnReplace(ctx, userID, guard, kind, before, after, audit.ActorFromContext(ctx)); err != nil {
+		log.Printf("go-role: audit: failed to record replace of %s for user %s under guard %q: %v", kind, userID, guard, err)
+	}
+}
+
+// newIDs returns the ids in requested that are not present in existing, so
+// that audit entries only ever cover ids that were actually granted and not
+// silently skipped by an OnConflict{DoNothing: true} upsert.
+func newIDs(requested []uint, existing []uint) []uint {
+	if len(existing) == 0 {
+		return requested
+	}
+	skip := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		skip[id] = true
+	}
+	var ids []uint
+	for _, id := range requested {
+		if !skip[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
 
 // ACTIONS
 
-// AddPermissions add direct permissions to user.
+// AddPermissions add direct permissions to user within the given guard.
 // @param uuid.UUID
+// @param string
 // @param collections.Permission
 // @return error
-func (repository *UserRepository) AddPermissions(userID uuid.UUID, permissions collections.Permission) error {
-	var userPermissions []pivot.UserPermissions
-	for _, permission := range permissions.Origin() {
-		userPermissions = append(userPermissions, pivot.UserPermissions{
-			UserID:       userID,
-			PermissionID: permission.ID,
-		})
+func (repository *UserRepository) AddPermissions(userID uuid.UUID, guard string, permissions collections.Permission) error {
+	return repository.AddPermissionsContext(context.Background(), userID, guard, permissions)
+}
+
+// AddPermissionsContext add direct permissions to user within the given guard.
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param collections.Permission
+// @return error
+func (repository *UserRepository) AddPermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) error {
+	var existing []uint
+	err := repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Where("user_permissions.permission_id IN (?)", permissions.IDs()).Pluck("user_permissions.permission_id", &existing).Error; err != nil {
+			return err
+		}
+
+		var userPermissions []pivot.UserPermissions
+		for _, permission := range permissions.Origin() {
+			userPermissions = append(userPermissions, pivot.UserPermissions{
+				UserID:       userID,
+				PermissionID: permission.ID,
+				GuardName:    guard,
+			})
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userPermissions).Error
+	})
+	if err != nil {
+		return err
 	}
-	return repository.Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&userPermissions).Error
+	repository.recordGrant(ctx, userID, guard, audit.KindPermission, newIDs(permissions.IDs(), existing))
+	return nil
+}
+
+// ReplacePermissions replace direct permissions of user within the given guard.
+// @param uuid.UUID
+// @param string
+// @param collections.Permission
+// @return error
+func (repository *UserRepository) ReplacePermissions(userID uuid.UUID, guard string, permissions collections.Permission) error {
+	return repository.ReplacePermissionsContext(context.Background(), userID, guard, permissions)
 }
 
-// ReplacePermissions replace direct permissions of user.
+// ReplacePermissionsContext replace direct permissions of user within the given guard.
+// @param context.Context
 // @param uuid.UUID
+// @param string
 // @param collections.Permission
 // @return error
-func (repository *UserRepository) ReplacePermissions(userID uuid.UUID, permissions collections.Permission) error {
-	return repository.Database.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("user_permissions.user_id = ?", userID).Delete(&pivot.UserPermissions{}).Error; err != nil {
+func (repository *UserRepository) ReplacePermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) error {
+	var before []uint
+	err := repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Pluck("user_permissions.permission_id", &before).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Delete(&pivot.UserPermissions{}).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
@@ -73,6 +233,7 @@ func (repository *UserRepository) ReplacePermissions(userID uuid.UUID, permissio
 			userPermissions = append(userPermissions, pivot.UserPermissions{
 				UserID:       userID,
 				PermissionID: permission.ID,
+				GuardName:    guard,
 			})
 		}
 
@@ -83,60 +244,150 @@ func (repository *UserRepository) ReplacePermissions(userID uuid.UUID, permissio
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	repository.recordReplace(ctx, userID, guard, audit.KindPermission, before, permissions.IDs())
+	return nil
 }
 
-// RemovePermissions remove direct permissions of user.
+// RemovePermissions remove direct permissions of user within the given guard.
 // @param uuid.UUID
+// @param string
 // @param collections.Permission
 // @return error
-func (repository *UserRepository) RemovePermissions(userID uuid.UUID, permissions collections.Permission) error {
-	var userPermissions []pivot.UserPermissions
-	for _, permission := range permissions.Origin() {
-		userPermissions = append(userPermissions, pivot.UserPermissions{
-			UserID:       userID,
-			PermissionID: permission.ID,
-		})
+func (repository *UserRepository) RemovePermissions(userID uuid.UUID, guard string, permissions collections.Permission) error {
+	return repository.RemovePermissionsContext(context.Background(), userID, guard, permissions)
+}
+
+// RemovePermissionsContext remove direct permissions of user within the given guard.
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param collections.Permission
+// @return error
+func (repository *UserRepository) RemovePermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) error {
+	var held []uint
+	err := repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Where("user_permissions.permission_id IN (?)", permissions.IDs()).Pluck("user_permissions.permission_id", &held).Error; err != nil {
+			return err
+		}
+
+		var userPermissions []pivot.UserPermissions
+		for _, permission := range permissions.Origin() {
+			userPermissions = append(userPermissions, pivot.UserPermissions{
+				UserID:       userID,
+				PermissionID: permission.ID,
+				GuardName:    guard,
+			})
+		}
+		return tx.Delete(&userPermissions).Error
+	})
+	if err != nil {
+		return err
 	}
-	return repository.Database.Delete(&userPermissions).Error
+	repository.recordRevoke(ctx, userID, guard, audit.KindPermission, held)
+	return nil
 }
 
-// ClearPermissions remove all direct permissions of user.
+// ClearPermissions remove all direct permissions of user within the given guard.
 // @param uuid.UUID
+// @param string
 // @return error
-func (repository *UserRepository) ClearPermissions(userID uuid.UUID) (err error) {
-	return repository.Database.Where("user_permissions.user_id = ?", userID).Delete(&pivot.UserPermissions{}).Error
+func (repository *UserRepository) ClearPermissions(userID uuid.UUID, guard string) (err error) {
+	return repository.ClearPermissionsContext(context.Background(), userID, guard)
 }
 
-// AddRoles add roles to user.
+// ClearPermissionsContext remove all direct permissions of user within the given guard.
+// @param context.Context
 // @param uuid.UUID
+// @param string
+// @return error
+func (repository *UserRepository) ClearPermissionsContext(ctx context.Context, userID uuid.UUID, guard string) (err error) {
+	var ids []uint
+	err = repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Pluck("user_permissions.permission_id", &ids).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Delete(&pivot.UserPermissions{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	repository.recordRevoke(ctx, userID, guard, audit.KindPermission, ids)
+	return nil
+}
+
+// AddRoles add roles to user within the given guard.
+// @param uuid.UUID
+// @param string
 // @param collections.Role
 // @return error
-func (repository *UserRepository) AddRoles(userID uuid.UUID, roles collections.Role) error {
-	var userRoles []pivot.UserRoles
-	for _, role := range roles.Origin() {
-		userRoles = append(userRoles, pivot.UserRoles{
-			UserID: userID,
-			RoleID: role.ID,
-		})
+func (repository *UserRepository) AddRoles(userID uuid.UUID, guard string, roles collections.Role) error {
+	return repository.AddRolesContext(context.Background(), userID, guard, roles)
+}
+
+// AddRolesContext add roles to user within the given guard.
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param collections.Role
+// @return error
+func (repository *UserRepository) AddRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) error {
+	var existing []uint
+	err := repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Where("user_roles.role_id IN (?)", roles.IDs()).Pluck("user_roles.role_id", &existing).Error; err != nil {
+			return err
+		}
+
+		var userRoles []pivot.UserRoles
+		for _, role := range roles.Origin() {
+			userRoles = append(userRoles, pivot.UserRoles{
+				UserID:    userID,
+				RoleID:    role.ID,
+				GuardName: guard,
+			})
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRoles).Error
+	})
+	if err != nil {
+		return err
 	}
-	return repository.Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRoles).Error
+	repository.recordGrant(ctx, userID, guard, audit.KindRole, newIDs(roles.IDs(), existing))
+	return nil
 }
 
-// ReplaceRoles replace roles of user.
+// ReplaceRoles replace roles of user within the given guard.
 // @param uuid.UUID
+// @param string
 // @param collections.Role
 // @return error
-func (repository *UserRepository) ReplaceRoles(userID uuid.UUID, roles collections.Role) error {
-	return repository.Database.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("user_roles.user_id = ?", userID).Delete(&pivot.UserRoles{}).Error; err != nil {
+func (repository *UserRepository) ReplaceRoles(userID uuid.UUID, guard string, roles collections.Role) error {
+	return repository.ReplaceRolesContext(context.Background(), userID, guard, roles)
+}
+
+// ReplaceRolesContext replace roles of user within the given guard.
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param collections.Role
+// @return error
+func (repository *UserRepository) ReplaceRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) error {
+	var before []uint
+	err := repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Pluck("user_roles.role_id", &before).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Delete(&pivot.UserRoles{}).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
 		var userRoles []pivot.UserRoles
 		for _, role := range roles.Origin() {
 			userRoles = append(userRoles, pivot.UserRoles{
-				UserID: userID,
-				RoleID: role.ID,
+				UserID:    userID,
+				RoleID:    role.ID,
+				GuardName: guard,
 			})
 		}
 		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRoles).Error; err != nil {
@@ -145,88 +396,436 @@ func (repository *UserRepository) ReplaceRoles(userID uuid.UUID, roles collectio
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	repository.recordReplace(ctx, userID, guard, audit.KindRole, before, roles.IDs())
+	return nil
 }
 
-// RemoveRoles remove roles of user.
+// RemoveRoles remove roles of user within the given guard.
 // @param uuid.UUID
+// @param string
 // @param collections.Role
 // @return error
-func (repository *UserRepository) RemoveRoles(userID uuid.UUID, roles collections.Role) error {
-	var userRoles []pivot.UserRoles
-	for _, role := range roles.Origin() {
-		userRoles = append(userRoles, pivot.UserRoles{
-			UserID: userID,
-			RoleID: role.ID,
-		})
+func (repository *UserRepository) RemoveRoles(userID uuid.UUID, guard string, roles collections.Role) error {
+	return repository.RemoveRolesContext(context.Background(), userID, guard, roles)
+}
+
+// RemoveRolesContext remove roles of user within the given guard.
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param collections.Role
+// @return error
+func (repository *UserRepository) RemoveRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) error {
+	var held []uint
+	err := repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Where("user_roles.role_id IN (?)", roles.IDs()).Pluck("user_roles.role_id", &held).Error; err != nil {
+			return err
+		}
+
+		var userRoles []pivot.UserRoles
+		for _, role := range roles.Origin() {
+			userRoles = append(userRoles, pivot.UserRoles{
+				UserID:    userID,
+				RoleID:    role.ID,
+				GuardName: guard,
+			})
+		}
+		return tx.Delete(&userRoles).Error
+	})
+	if err != nil {
+		return err
 	}
-	return repository.Database.Delete(&userRoles).Error
+	repository.recordRevoke(ctx, userID, guard, audit.KindRole, held)
+	return nil
 }
 
-// ClearRoles remove all roles of user.
+// ClearRoles remove all roles of user within the given guard.
 // @param uuid.UUID
+// @param string
 // @return error
-func (repository *UserRepository) ClearRoles(userID uuid.UUID) (err error) {
-	return repository.Database.Where("user_roles.user_id = ?", userID).Delete(&pivot.UserRoles{}).Error
+func (repository *UserRepository) ClearRoles(userID uuid.UUID, guard string) (err error) {
+	return repository.ClearRolesContext(context.Background(), userID, guard)
+}
+
+// ClearRolesContext remove all roles of user within the given guard.
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @return error
+func (repository *UserRepository) ClearRolesContext(ctx context.Context, userID uuid.UUID, guard string) (err error) {
+	var ids []uint
+	err = repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Pluck("user_roles.role_id", &ids).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Delete(&pivot.UserRoles{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	repository.recordRevoke(ctx, userID, guard, audit.KindRole, ids)
+	return nil
 }
 
 // CONTROLS
 
-// HasRole does the user have the given role?
+// HasRole does the user have the given role within the given guard?
 // @param uuid.UUID
+// @param string
 // @param models.Role
 // @return bool, error
-func (repository *UserRepository) HasRole(userID uuid.UUID, role models.Role) (b bool, err error) {
+func (repository *UserRepository) HasRole(userID uuid.UUID, guard string, role models.Role) (b bool, err error) {
+	return repository.HasRoleContext(context.Background(), userID, guard, role)
+}
+
+// HasRoleContext does the user have the given role within the given guard?
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param models.Role
+// @return bool, error
+func (repository *UserRepository) HasRoleContext(ctx context.Context, userID uuid.UUID, guard string, role models.Role) (b bool, err error) {
 	var count int64
-	err = repository.Database.Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.role_id = ?", role.ID).Count(&count).Error
+	err = repository.Database.WithContext(ctx).Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Where("user_roles.role_id = ?", role.ID).Count(&count).Error
 	return count > 0, err
 }
 
-// HasAllRoles does the user have all the given roles?
+// HasAllRoles does the user have all the given roles within the given guard?
 // @param uuid.UUID
+// @param string
 // @param collections.Role
 // @return bool, error
-func (repository *UserRepository) HasAllRoles(userID uuid.UUID, roles collections.Role) (b bool, err error) {
+func (repository *UserRepository) HasAllRoles(userID uuid.UUID, guard string, roles collections.Role) (b bool, err error) {
+	return repository.HasAllRolesContext(context.Background(), userID, guard, roles)
+}
+
+// HasAllRolesContext does the user have all the given roles within the given guard?
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param collections.Role
+// @return bool, error
+func (repository *UserRepository) HasAllRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) (b bool, err error) {
 	var count int64
-	err = repository.Database.Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.role_id IN (?)", roles.IDs()).Count(&count).Error
+	err = repository.Database.WithContext(ctx).Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Where("user_roles.role_id IN (?)", roles.IDs()).Count(&count).Error
 	return roles.Len() == count, err
 }
 
-// HasAnyRoles does the user have any of the given roles?
+// HasAnyRoles does the user have any of the given roles within the given guard?
+// @param uuid.UUID
+// @param string
+// @param collections.Role
+// @return bool, error
+func (repository *UserRepository) HasAnyRoles(userID uuid.UUID, guard string, roles collections.Role) (b bool, err error) {
+	return repository.HasAnyRolesContext(context.Background(), userID, guard, roles)
+}
+
+// HasAnyRolesContext does the user have any of the given roles within the given guard?
+// @param context.Context
 // @param uuid.UUID
+// @param string
 // @param collections.Role
 // @return bool, error
-func (repository *UserRepository) HasAnyRoles(userID uuid.UUID, roles collections.Role) (b bool, err error) {
+func (repository *UserRepository) HasAnyRolesContext(ctx context.Context, userID uuid.UUID, guard string, roles collections.Role) (b bool, err error) {
 	var count int64
-	err = repository.Database.Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.role_id IN (?)", roles.IDs()).Count(&count).Error
+	err = repository.Database.WithContext(ctx).Table("user_roles").Where("user_roles.user_id = ?", userID).Where("user_roles.guard_name = ?", guard).Where("user_roles.role_id IN (?)", roles.IDs()).Count(&count).Error
 	return count > 0, err
 }
 
-// HasDirectPermission does the user have the given permission? (not including the permissions of the roles)
+// HasDirectPermission does the user have the given permission within the given guard? (not including the permissions of the roles)
+// @param uuid.UUID
+// @param string
+// @param models.Permission
+// @return bool, error
+func (repository *UserRepository) HasDirectPermission(userID uuid.UUID, guard string, permission models.Permission) (b bool, err error) {
+	return repository.HasDirectPermissionContext(context.Background(), userID, guard, permission)
+}
+
+// HasDirectPermissionContext does the user have the given permission within the given guard? (not including the permissions of the roles)
+// @param context.Context
 // @param uuid.UUID
+// @param string
 // @param collections.Permission
 // @return bool, error
-func (repository *UserRepository) HasDirectPermission(userID uuid.UUID, permission models.Permission) (b bool, err error) {
+func (repository *UserRepository) HasDirectPermissionContext(ctx context.Context, userID uuid.UUID, guard string, permission models.Permission) (b bool, err error) {
 	var count int64
-	err = repository.Database.Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.permission_id = ?", permission.ID).Count(&count).Error
+	err = repository.Database.WithContext(ctx).Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Where("user_permissions.permission_id = ?", permission.ID).Count(&count).Error
 	return count > 0, err
 }
 
-// HasAllDirectPermissions does the user have all the given permissions? (not including the permissions of the roles)
+// HasAllDirectPermissions does the user have all the given permissions within the given guard? (not including the permissions of the roles)
+// @param uuid.UUID
+// @param string
+// @param collections.Permission
+// @return bool, error
+func (repository *UserRepository) HasAllDirectPermissions(userID uuid.UUID, guard string, permissions collections.Permission) (b bool, err error) {
+	return repository.HasAllDirectPermissionsContext(context.Background(), userID, guard, permissions)
+}
+
+// HasAllDirectPermissionsContext does the user have all the given permissions within the given guard? (not including the permissions of the roles)
+// @param context.Context
 // @param uuid.UUID
+// @param string
 // @param collections.Permission
 // @return bool, error
-func (repository *UserRepository) HasAllDirectPermissions(userID uuid.UUID, permissions collections.Permission) (b bool, err error) {
+func (repository *UserRepository) HasAllDirectPermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) (b bool, err error) {
 	var count int64
-	err = repository.Database.Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.permission_id IN (?)", permissions.IDs()).Count(&count).Error
+	err = repository.Database.WithContext(ctx).Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Where("user_permissions.permission_id IN (?)", permissions.IDs()).Count(&count).Error
 	return permissions.Len() == count, err
 }
 
-// HasAnyDirectPermissions does the user have any of the given permissions? (not including the permissions of the roles)
+// HasAnyDirectPermissions does the user have any of the given permissions within the given guard? (not including the permissions of the roles)
 // @param uuid.UUID
+// @param string
 // @param collections.Permission
 // @return bool, error
-func (repository *UserRepository) HasAnyDirectPermissions(userID uuid.UUID, permissions collections.Permission) (b bool, err error) {
+func (repository *UserRepository) HasAnyDirectPermissions(userID uuid.UUID, guard string, permissions collections.Permission) (b bool, err error) {
+	return repository.HasAnyDirectPermissionsContext(context.Background(), userID, guard, permissions)
+}
+
+// HasAnyDirectPermissionsContext does the user have any of the given permissions within the given guard? (not including the permissions of the roles)
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param collections.Permission
+// @return bool, error
+func (repository *UserRepository) HasAnyDirectPermissionsContext(ctx context.Context, userID uuid.UUID, guard string, permissions collections.Permission) (b bool, err error) {
 	var count int64
-	err = repository.Database.Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.permission_id IN (?)", permissions.IDs()).Count(&count).Error
+	err = repository.Database.WithContext(ctx).Table("user_permissions").Where("user_permissions.user_id = ?", userID).Where("user_permissions.guard_name = ?", guard).Where("user_permissions.permission_id IN (?)", permissions.IDs()).Count(&count).Error
 	return count > 0, err
 }
+
+// GetAllPermissionsOfUser returns the deduplicated union of the user's direct
+// permissions and the permissions granted through their roles within the given
+// guard, following role inheritance (a role's permissions are also granted to
+// its child roles).
+// @param uuid.UUID
+// @param string
+// @return collections.Permission, error
+func (repository *UserRepository) GetAllPermissionsOfUser(userID uuid.UUID, guard string) (collections.Permission, error) {
+	return repository.GetAllPermissionsOfUserContext(context.Background(), userID, guard)
+}
+
+// GetAllPermissionsOfUserContext returns the deduplicated union of the user's direct
+// permissions and the permissions granted through their roles within the given
+// guard, following role inheritance (a role's permissions are also granted to
+// its child roles).
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @return collections.Permission, error
+func (repository *UserRepository) GetAllPermissionsOfUserContext(ctx context.Context, userID uuid.UUID, guard string) (collections.Permission, error) {
+	var permissions []models.Permission
+	err := repository.Database.WithContext(ctx).Raw(
+		pivot.RoleTreeCTE(`SELECT role_id FROM user_roles WHERE user_id = ? AND guard_name = ?`)+`
+		SELECT DISTINCT p.* FROM permissions p
+		INNER JOIN role_permissions rp ON rp.permission_id = p.id
+		INNER JOIN role_tree rt ON rt.role_id = rp.role_id AND rp.guard_name = ?
+		UNION
+		SELECT DISTINCT p.* FROM permissions p
+		INNER JOIN user_permissions up ON up.permission_id = p.id
+		WHERE up.user_id = ? AND up.guard_name = ?
+	`, userID, guard, guard, guard, userID, guard).Scan(&permissions).Error
+	if err != nil {
+		return collections.Permission{}, err
+	}
+	return collections.Permission(permissions), nil
+}
+
+// HasPermission does the user have the given permission within the given
+// guard, either directly or through any of their roles (including inherited
+// roles)?
+// @param uuid.UUID
+// @param string
+// @param models.Permission
+// @return bool, error
+func (repository *UserRepository) HasPermission(userID uuid.UUID, guard string, permission models.Permission) (b bool, err error) {
+	return repository.HasPermissionContext(context.Background(), userID, guard, permission)
+}
+
+// HasPermissionContext does the user have the given permission within the given
+// guard, either directly or through any of their roles (including inherited
+// roles)?
+// @param context.Context
+// @param uuid.UUID
+// @param string
+// @param models.Permission
+// @return bool, error
+func (repository *UserRepository) HasPermissionContext(ctx context.Context, userID uuid.UUID, guard string, permission models.Permission) (b bool, err error) {
+	permissions, err := repository.GetAllPermissionsOfUserContext(ctx, userID, guard)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions.Origin() {
+		if p.ID == permission.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// BULK OPERATIONS
+
+// SyncUserRoles assigns roles to many users with a single INSERT, instead of
+// one INSERT per user, preceded by one SELECT of the pairs already held.
+// Existing (user, role, guard) pairs are left untouched.
+// @param string
+// @param map[uuid.UUID]collections.Role
+// @return error
+func (repository *UserRepository) SyncUserRoles(guard string, assignments map[uuid.UUID]collections.Role) error {
+	return repository.SyncUserRolesContext(context.Background(), guard, assignments)
+}
+
+// SyncUserRolesContext assigns roles to many users with a single INSERT, instead of
+// one INSERT per user, preceded by one SELECT of the pairs already held.
+// Existing (user, role, guard) pairs are left untouched.
+// @param context.Context
+// @param string
+// @param map[uuid.UUID]collections.Role
+// @return error
+func (repository *UserRepository) SyncUserRolesContext(ctx context.Context, guard string, assignments map[uuid.UUID]collections.Role) error {
+	userIDs := make([]uuid.UUID, 0, len(assignments))
+	roleIDSet := make(map[uint]bool)
+	var userRoles []pivot.UserRoles
+	for userID, roles := range assignments {
+		userIDs = append(userIDs, userID)
+		for _, role := range roles.Origin() {
+			userRoles = append(userRoles, pivot.UserRoles{
+				UserID:    userID,
+				RoleID:    role.ID,
+				GuardName: guard,
+			})
+			roleIDSet[role.ID] = true
+		}
+	}
+	if len(userRoles) == 0 {
+		return nil
+	}
+	roleIDs := make([]uint, 0, len(roleIDSet))
+	for id := range roleIDSet {
+		roleIDs = append(roleIDs, id)
+	}
+
+	existingByUser := make(map[uuid.UUID][]uint, len(userIDs))
+	err := repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existingRows []pivot.UserRoles
+		if err := tx.Table("user_roles").Where("user_roles.user_id IN (?)", userIDs).Where("user_roles.guard_name = ?", guard).Where("user_roles.role_id IN (?)", roleIDs).Find(&existingRows).Error; err != nil {
+			return err
+		}
+		for _, row := range existingRows {
+			existingByUser[row.UserID] = append(existingByUser[row.UserID], row.RoleID)
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRoles).Error
+	})
+	if err != nil {
+		return err
+	}
+	for userID, roles := range assignments {
+		repository.recordGrant(ctx, userID, guard, audit.KindRole, newIDs(roles.IDs(), existingByUser[userID]))
+	}
+	return nil
+}
+
+// SyncUserPermissions assigns direct permissions to many users with a single
+// INSERT, instead of one INSERT per user, preceded by one SELECT of the pairs
+// already held. Existing (user, permission, guard) pairs are left untouched.
+// @param string
+// @param map[uuid.UUID]collections.Permission
+// @return error
+func (repository *UserRepository) SyncUserPermissions(guard string, assignments map[uuid.UUID]collections.Permission) error {
+	return repository.SyncUserPermissionsContext(context.Background(), guard, assignments)
+}
+
+// SyncUserPermissionsContext assigns direct permissions to many users with a single
+// INSERT, instead of one INSERT per user, preceded by one SELECT of the pairs
+// already held. Existing (user, permission, guard) pairs are left untouched.
+// @param context.Context
+// @param string
+// @param map[uuid.UUID]collections.Permission
+// @return error
+func (repository *UserRepository) SyncUserPermissionsContext(ctx context.Context, guard string, assignments map[uuid.UUID]collections.Permission) error {
+	userIDs := make([]uuid.UUID, 0, len(assignments))
+	permissionIDSet := make(map[uint]bool)
+	var userPermissions []pivot.UserPermissions
+	for userID, permissions := range assignments {
+		userIDs = append(userIDs, userID)
+		for _, permission := range permissions.Origin() {
+			userPermissions = append(userPermissions, pivot.UserPermissions{
+				UserID:       userID,
+				PermissionID: permission.ID,
+				GuardName:    guard,
+			})
+			permissionIDSet[permission.ID] = true
+		}
+	}
+	if len(userPermissions) == 0 {
+		return nil
+	}
+	permissionIDs := make([]uint, 0, len(permissionIDSet))
+	for id := range permissionIDSet {
+		permissionIDs = append(permissionIDs, id)
+	}
+
+	existingByUser := make(map[uuid.UUID][]uint, len(userIDs))
+	err := repository.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existingRows []pivot.UserPermissions
+		if err := tx.Table("user_permissions").Where("user_permissions.user_id IN (?)", userIDs).Where("user_permissions.guard_name = ?", guard).Where("user_permissions.permission_id IN (?)", permissionIDs).Find(&existingRows).Error; err != nil {
+			return err
+		}
+		for _, row := range existingRows {
+			existingByUser[row.UserID] = append(existingByUser[row.UserID], row.PermissionID)
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userPermissions).Error
+	})
+	if err != nil {
+		return err
+	}
+	for userID, permissions := range assignments {
+		repository.recordGrant(ctx, userID, guard, audit.KindPermission, newIDs(permissions.IDs(), existingByUser[userID]))
+	}
+	return nil
+}
+
+// HasRolesForUsers checks whether the given role is held by each of the given
+// users within the given guard, with a single grouped query instead of one
+// lookup per user.
+// @param []uuid.UUID
+// @param string
+// @param models.Role
+// @return map[uuid.UUID]bool, error
+func (repository *UserRepository) HasRolesForUsers(userIDs []uuid.UUID, guard string, role models.Role) (map[uuid.UUID]bool, error) {
+	return repository.HasRolesForUsersContext(context.Background(), userIDs, guard, role)
+}
+
+// HasRolesForUsersContext checks whether the given role is held by each of the given
+// users within the given guard, with a single grouped query instead of one
+// lookup per user.
+// @param context.Context
+// @param []uuid.UUID
+// @param string
+// @param models.Role
+// @return map[uuid.UUID]bool, error
+func (repository *UserRepository) HasRolesForUsersContext(ctx context.Context, userIDs []uuid.UUID, guard string, role models.Role) (map[uuid.UUID]bool, error) {
+	var matched []uuid.UUID
+	err := repository.Database.WithContext(ctx).
+		Table("user_roles").
+		Where("user_roles.user_id IN (?)", userIDs).
+		Where("user_roles.guard_name = ?", guard).
+		Where("user_roles.role_id = ?", role.ID).
+		Pluck("user_roles.user_id", &matched).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]bool, len(userIDs))
+	for _, userID := range userIDs {
+		result[userID] = false
+	}
+	for _, userID := range matched {
+		result[userID] = true
+	}
+	return result, nil
+}
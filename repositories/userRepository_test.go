@@ -0,0 +1,309 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/google/uuid"
+
+	"github.com/Permify/go-role/audit"
+	"github.com/Permify/go-role/collections"
+	"github.com/Permify/go-role/models"
+	"github.com/Permify/go-role/models/pivot"
+)
+
+// errRollback is returned from inside a db.Transaction closure purely to
+// force a rollback in tests, without it being mistaken for a real failure.
+var errRollback = errors.New("repositories: test-induced rollback")
+
+// countingRecorder counts how many times OnGrant fires per id, so tests can
+// assert that a no-op re-grant (one that OnConflict{DoNothing: true} already
+// skipped) does not produce a duplicate audit entry.
+type countingRecorder struct {
+	grants map[uint]int
+}
+
+func newCountingRecorder() *countingRecorder {
+	return &countingRecorder{grants: make(map[uint]int)}
+}
+
+func (r *countingRecorder) OnGrant(ctx context.Context, userID uuid.UUID, guard string, kind audit.Kind, ids []uint, actor uuid.UUID) error {
+	for _, id := range ids {
+		r.grants[id]++
+	}
+	return nil
+}
+
+func (r *countingRecorder) OnRevoke(ctx context.Context, userID uuid.UUID, guard string, kind audit.Kind, ids []uint, actor uuid.UUID) error {
+	return nil
+}
+
+func (r *countingRecorder) OnReplace(ctx context.Context, userID uuid.UUID, guard string, kind audit.Kind, before []uint, after []uint, actor uuid.UUID) error {
+	return nil
+}
+
+func TestAddPermissions_GuardIsolation(t *testing.T) {
+	db := newTestDB(t)
+	repository := &UserRepository{Database: db}
+	userID := uuid.New()
+
+	permission := models.Permission{Name: "orders.read"}
+	if err := db.Create(&permission).Error; err != nil {
+		t.Fatalf("create permission: %v", err)
+	}
+
+	if err := repository.AddPermissions(userID, "web", collections.Permission{permission}); err != nil {
+		t.Fatalf("AddPermissions(web): %v", err)
+	}
+
+	hasWeb, err := repository.HasDirectPermission(userID, "web", permission)
+	if err != nil {
+		t.Fatalf("HasDirectPermission(web): %v", err)
+	}
+	if !hasWeb {
+		t.Errorf("user should hold permission %d under guard %q", permission.ID, "web")
+	}
+
+	hasAPI, err := repository.HasDirectPermission(userID, "api", permission)
+	if err != nil {
+		t.Fatalf("HasDirectPermission(api): %v", err)
+	}
+	if hasAPI {
+		t.Errorf("permission %d granted under guard %q leaked into guard %q", permission.ID, "web", "api")
+	}
+}
+
+func TestAddPermissions_NoDuplicateAuditOnReGrant(t *testing.T) {
+	db := newTestDB(t)
+	recorder := newCountingRecorder()
+	repository := &UserRepository{Database: db, Recorder: recorder}
+	userID := uuid.New()
+
+	permission := models.Permission{Name: "orders.read"}
+	if err := db.Create(&permission).Error; err != nil {
+		t.Fatalf("create permission: %v", err)
+	}
+
+	if err := repository.AddPermissionsContext(context.Background(), userID, "web", collections.Permission{permission}); err != nil {
+		t.Fatalf("first AddPermissionsContext: %v", err)
+	}
+	if err := repository.AddPermissionsContext(context.Background(), userID, "web", collections.Permission{permission}); err != nil {
+		t.Fatalf("second (no-op) AddPermissionsContext: %v", err)
+	}
+
+	if got := recorder.grants[permission.ID]; got != 1 {
+		t.Errorf("recorder.OnGrant fired %d times for permission %d, want 1 (the re-grant was a no-op)", got, permission.ID)
+	}
+}
+
+func TestSyncUserRoles_NoDuplicateAuditOnReSync(t *testing.T) {
+	db := newTestDB(t)
+	recorder := newCountingRecorder()
+	repository := &UserRepository{Database: db, Recorder: recorder}
+	userID := uuid.New()
+
+	role := models.Role{Name: "editor"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+
+	assignments := map[uuid.UUID]collections.Role{userID: {role}}
+	if err := repository.SyncUserRolesContext(context.Background(), "web", assignments); err != nil {
+		t.Fatalf("first SyncUserRolesContext: %v", err)
+	}
+	if err := repository.SyncUserRolesContext(context.Background(), "web", assignments); err != nil {
+		t.Fatalf("second (no-op) SyncUserRolesContext: %v", err)
+	}
+
+	if got := recorder.grants[role.ID]; got != 1 {
+		t.Errorf("recorder.OnGrant fired %d times for role %d, want 1 (the re-sync was a no-op)", got, role.ID)
+	}
+}
+
+// TestWithTx_RecorderRollsBackWithTransaction pins down that a Recorder
+// composed through WithTx is rebound to the caller's transaction: when the
+// caller rolls back, the audit entry must roll back with it, not persist a
+// record of a grant that never actually took effect.
+func TestWithTx_RecorderRollsBackWithTransaction(t *testing.T) {
+	db := newTestDB(t)
+	recorder := &audit.GormRecorder{Database: db}
+	repository := &UserRepository{Database: db, Recorder: recorder}
+	userID := uuid.New()
+
+	role := models.Role{Name: "editor"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		txRepository := repository.WithTx(tx)
+		if err := txRepository.AddRolesContext(context.Background(), userID, "web", collections.Role{role}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("db.Transaction returned %v, want errRollback", err)
+	}
+
+	hasRole, err := repository.HasRole(userID, "web", role)
+	if err != nil {
+		t.Fatalf("HasRole: %v", err)
+	}
+	if hasRole {
+		t.Errorf("role grant should have rolled back with the outer transaction")
+	}
+
+	var auditCount int64
+	if err := db.Model(&audit.Log{}).Count(&auditCount).Error; err != nil {
+		t.Fatalf("count audit log: %v", err)
+	}
+	if auditCount != 0 {
+		t.Errorf("audit log has %d rows, want 0: the Recorder's write should have rolled back along with the grant it describes", auditCount)
+	}
+}
+
+// TestWithTx_RecorderCommitsWithTransaction is the commit-path counterpart to
+// TestWithTx_RecorderRollsBackWithTransaction: it pins down that the
+// Recorder's write actually lands when the outer transaction commits. Before
+// WithTx rebound the Recorder to tx, this failed with "database table is
+// locked" under sqlite's single-writer model, since the Recorder wrote
+// through the original top-level *gorm.DB while the outer transaction still
+// held the write lock on a separate connection.
+func TestWithTx_RecorderCommitsWithTransaction(t *testing.T) {
+	db := newTestDB(t)
+	recorder := &audit.GormRecorder{Database: db}
+	repository := &UserRepository{Database: db, Recorder: recorder}
+	userID := uuid.New()
+
+	role := models.Role{Name: "editor"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		txRepository := repository.WithTx(tx)
+		return txRepository.AddRolesContext(context.Background(), userID, "web", collections.Role{role})
+	})
+	if err != nil {
+		t.Fatalf("db.Transaction: %v", err)
+	}
+
+	var auditCount int64
+	if err := db.Model(&audit.Log{}).Count(&auditCount).Error; err != nil {
+		t.Fatalf("count audit log: %v", err)
+	}
+	if auditCount != 1 {
+		t.Errorf("audit log has %d rows, want 1: the Recorder's write should go through on the same transaction/connection as the grant it describes", auditCount)
+	}
+}
+
+// TestAddRoles_AuditRecordsGuard pins down that the guard a mutation happened
+// under is persisted on its audit.Log row, not just the ids and actor: a
+// multi-tenant (guard-scoped) audit trail that can't say which guard/tenant a
+// grant applied to can't support per-tenant history or compliance review.
+func TestAddRoles_AuditRecordsGuard(t *testing.T) {
+	db := newTestDB(t)
+	recorder := &audit.GormRecorder{Database: db}
+	repository := &UserRepository{Database: db, Recorder: recorder}
+	userID := uuid.New()
+
+	role := models.Role{Name: "editor"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+
+	if err := repository.AddRoles(userID, "api", collections.Role{role}); err != nil {
+		t.Fatalf("AddRoles: %v", err)
+	}
+
+	var entry audit.Log
+	if err := db.First(&entry).Error; err != nil {
+		t.Fatalf("load audit log entry: %v", err)
+	}
+	if entry.Guard != "api" {
+		t.Errorf("audit.Log.Guard = %q, want %q", entry.Guard, "api")
+	}
+}
+
+// TestGetAllPermissionsOfUser_DirectAndInheritedUnion pins down the headline
+// behavior of GetAllPermissionsOfUser: the union of a user's direct
+// permissions and the permissions granted through their roles, including
+// permissions inherited from a role's parent, deduplicated and scoped to
+// guard. This exercises the user_roles-rooted query, which is distinct from
+// RoleRepository.GetAllPermissionsOfRoles (rooted at roles.id IN (?)).
+func TestGetAllPermissionsOfUser_DirectAndInheritedUnion(t *testing.T) {
+	db := newTestDB(t)
+	repository := &UserRepository{Database: db}
+	userID := uuid.New()
+
+	parent := models.Role{Name: "admin"}
+	child := models.Role{Name: "editor"}
+	if err := db.Create(&parent).Error; err != nil {
+		t.Fatalf("create parent role: %v", err)
+	}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatalf("create child role: %v", err)
+	}
+	if err := db.Create(&pivot.RoleHierarchy{ParentRoleID: parent.ID, ChildRoleID: child.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("create role hierarchy: %v", err)
+	}
+
+	directPermission := models.Permission{Name: "profile.edit"}
+	inheritedPermission := models.Permission{Name: "users.manage"}
+	duplicatePermission := models.Permission{Name: "posts.edit"}
+	if err := db.Create(&directPermission).Error; err != nil {
+		t.Fatalf("create direct permission: %v", err)
+	}
+	if err := db.Create(&inheritedPermission).Error; err != nil {
+		t.Fatalf("create inherited permission: %v", err)
+	}
+	if err := db.Create(&duplicatePermission).Error; err != nil {
+		t.Fatalf("create duplicate permission: %v", err)
+	}
+	if err := db.Create(&pivot.RolePermissions{RoleID: parent.ID, PermissionID: inheritedPermission.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("grant permission to parent role: %v", err)
+	}
+	if err := db.Create(&pivot.RolePermissions{RoleID: child.ID, PermissionID: duplicatePermission.ID, GuardName: "web"}).Error; err != nil {
+		t.Fatalf("grant permission to child role: %v", err)
+	}
+
+	if err := repository.AddRoles(userID, "web", collections.Role{child}); err != nil {
+		t.Fatalf("AddRoles: %v", err)
+	}
+	if err := repository.AddPermissions(userID, "web", collections.Permission{directPermission, duplicatePermission}); err != nil {
+		t.Fatalf("AddPermissions: %v", err)
+	}
+
+	permissions, err := repository.GetAllPermissionsOfUser(userID, "web")
+	if err != nil {
+		t.Fatalf("GetAllPermissionsOfUser: %v", err)
+	}
+	for _, want := range []models.Permission{directPermission, inheritedPermission, duplicatePermission} {
+		if !containsPermissionID(permissions, want.ID) {
+			t.Errorf("GetAllPermissionsOfUser missing permission %q", want.Name)
+		}
+	}
+	if got := permissions.Len(); got != 3 {
+		t.Errorf("GetAllPermissionsOfUser returned %d permissions, want 3 (duplicatePermission held both directly and via role must be deduplicated)", got)
+	}
+
+	hasInherited, err := repository.HasPermission(userID, "web", inheritedPermission)
+	if err != nil {
+		t.Fatalf("HasPermission(inherited): %v", err)
+	}
+	if !hasInherited {
+		t.Errorf("HasPermission should report true for a permission inherited from the role's parent")
+	}
+
+	hasInAPIGuard, err := repository.HasPermission(userID, "api", inheritedPermission)
+	if err != nil {
+		t.Fatalf("HasPermission(api): %v", err)
+	}
+	if hasInAPIGuard {
+		t.Errorf("HasPermission leaked a %q-guard role assignment into guard %q", "web", "api")
+	}
+}